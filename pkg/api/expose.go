@@ -0,0 +1,63 @@
+/*
+Copyright (C) 2019 Synopsys, Inc.
+
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements. See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership. The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License. You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied. See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package api
+
+// ExposeConfig describes how a product (Black Duck, Alert, Polaris) should be
+// exposed outside the cluster. It is platform-agnostic - the same config
+// produces an OpenShift Route on OpenShift and a Kubernetes Ingress
+// everywhere else, so product Creaters don't need to hand-roll a
+// LoadBalancer Service per platform.
+type ExposeConfig struct {
+	// Name is used as the Route/Ingress object name.
+	Name string
+	// Namespace the Route/Ingress is created in.
+	Namespace string
+	// Hostname is the external DNS name routed to the service. Left empty,
+	// OpenShift assigns the Route a default hostname; on plain Kubernetes the
+	// Ingress rule then has no host and matches any host.
+	Hostname string
+
+	// ServiceName and ServicePort identify the backend Service and port the
+	// Route/Ingress forwards traffic to.
+	ServiceName string
+	ServicePort int32
+
+	// TLS configuration. Cert/Key/CABundle are PEM-encoded. On OpenShift the
+	// Route gets a TLS section only when TLSTermination is set; on plain
+	// Kubernetes the Ingress gets TLS only when TLSCert or TLSKey is set.
+	TLSCert        string
+	TLSKey         string
+	TLSCABundle    string
+	TLSTermination string // "edge", "passthrough", or "reencrypt"
+
+	// WildcardPolicy mirrors route.openshift.io's WildcardPolicyType, e.g.
+	// "Subdomain" or "None". Ignored on plain Kubernetes.
+	WildcardPolicy string
+
+	// IngressClassName selects the Ingress controller on plain Kubernetes.
+	// Ignored on OpenShift, where Routes are handled by the built-in router.
+	IngressClassName string
+
+	// Annotations are copied onto the generated Route/Ingress object as-is,
+	// e.g. for controller-specific tuning (ingress.kubernetes.io/*).
+	Annotations map[string]string
+}
@@ -22,14 +22,18 @@ under the License.
 package blackduck
 
 import (
+	"errors"
 	"fmt"
+
 	horizonapi "github.com/blackducksoftware/horizon/pkg/api"
 	"github.com/blackducksoftware/horizon/pkg/components"
 	horizon "github.com/blackducksoftware/horizon/pkg/deployer"
 	"github.com/blackducksoftware/synopsys-operator/pkg/api/blackduck/v1"
 	"github.com/blackducksoftware/synopsys-operator/pkg/blackduck/containers"
 	"github.com/blackducksoftware/synopsys-operator/pkg/util"
+	"github.com/blackducksoftware/synopsysctl/pkg/storage"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 func (hc *Creater) init(deployer *horizon.Deployer, createHub *v1.BlackduckSpec, hubContainerFlavor *containers.ContainerFlavor,
@@ -63,64 +67,36 @@ func (hc *Creater) init(deployer *horizon.Deployer, createHub *v1.BlackduckSpec,
 	}
 
 	if createHub.PersistentStorage {
+		sizingPolicy, err := storage.NewPolicy()
+		if err != nil {
+			return fmt.Errorf("failed to load PVC sizing policy: %+v", err)
+		}
+
 		for _, claim := range createHub.PVC {
 			storageClass := createHub.PVCStorageClass
 			if len(claim.StorageClass) > 0 {
 				storageClass = claim.StorageClass
 			}
 
-			var size string
-
-			// Set default value if size isn't specified
-			// TODO JD - check the if the size is using a support format Gi, etc
-			switch claim.Name {
-			case "blackduck-postgres":
-				size = "150Gi"
-				if len(claim.Size) > 0 {
-					size = claim.Size
-				}
-			case "blackduck-authentication":
-				size = "2Gi"
-				if len(claim.Size) > 0 {
-					size = claim.Size
-				}
-			case "blackduck-cfssl":
-				size = "2Gi"
-				if len(claim.Size) > 0 {
-					size = claim.Size
-				}
-			case "blackduck-registration":
-				size = "2Gi"
-				if len(claim.Size) > 0 {
-					size = claim.Size
-				}
-			case "blackduck-solr":
-				size = "2Gi"
-				if len(claim.Size) > 0 {
-					size = claim.Size
-				}
-			case "blackduck-webapp":
-				size = "2Gi"
-				if len(claim.Size) > 0 {
-					size = claim.Size
-				}
-			case "blackduck-logstash":
-				size = "20Gi"
-				if len(claim.Size) > 0 {
-					size = claim.Size
-				}
-			case "blackduck-zookeeper-data":
-				size = "2Gi"
-				if len(claim.Size) > 0 {
-					size = claim.Size
+			size := claim.Size
+			if len(size) > 0 {
+				// Validate the user-supplied size is a well-formed quantity
+				// (e.g. "150Gi") before handing it to CreatePersistentVolumeClaim.
+				if _, err := resource.ParseQuantity(size); err != nil {
+					return fmt.Errorf("PVC %s has an invalid size '%s': %+v", claim.Name, size, err)
 				}
-			case "blackduck-zookeeper-datalog":
-				size = "2Gi"
-				if len(claim.Size) > 0 {
-					size = claim.Size
+			} else {
+				defaultSize, err := sizingPolicy.Default(claim.Name, "blackduck", createHub.Version)
+				switch {
+				case errors.Is(err, storage.ErrNoDefaultSize):
+					// No catalog opinion for this claim name - leave size blank,
+					// same as the old default: branch, and let the storage
+					// class/cluster decide.
+				case err != nil:
+					return fmt.Errorf("failed to determine default size for PVC %s: %+v", claim.Name, err)
+				default:
+					size = defaultSize.String()
 				}
-			default:
-				size = claim.Size
 			}
 
 			pvc, err := util.CreatePersistentVolumeClaim(claim.Name, createHub.Namespace, size, storageClass, horizonapi.ReadWriteOnce)
@@ -0,0 +1,167 @@
+/*
+Copyright (C) 2019 Synopsys, Inc.
+
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements. See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership. The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License. You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied. See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package storage
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed catalog.yaml
+var embeddedCatalogFS embed.FS
+
+// ErrNoDefaultSize is returned by Default when the catalog has no opinion on
+// claimName for product/version (unknown product, or a claim name the
+// catalog doesn't list). Callers that need to tolerate an unset size - e.g.
+// to preserve "leave it blank and let the cluster/storage class decide" -
+// can check for this with errors.Is.
+var ErrNoDefaultSize = errors.New("no default PVC size configured")
+
+// OverrideFile is the path passed via --pvc-sizing-file. When set, NewPolicy
+// layers its entries on top of the built-in catalog, letting an override win
+// per claim name without replacing the whole catalog. Accepts YAML or JSON -
+// sigs.k8s.io/yaml treats JSON as a strict subset of YAML.
+var OverrideFile string
+
+// productCatalog holds a product's default sizes, and optionally per-version
+// overrides of those defaults.
+type productCatalog struct {
+	Default  map[string]string            `json:"default"`
+	Versions map[string]map[string]string `json:"versions,omitempty"`
+}
+
+// catalogFile is the on-disk/embedded shape of the sizing catalog.
+type catalogFile struct {
+	Products map[string]productCatalog `json:"products"`
+}
+
+// Policy is the built-in SizingPolicy implementation. It is seeded from the
+// catalog embedded at build time and, if OverrideFile is set, merged with a
+// user-supplied catalog loaded at runtime.
+type Policy struct {
+	catalog catalogFile
+}
+
+// NewPolicy loads the embedded catalog and, if OverrideFile is set, merges in
+// the user-supplied catalog on top of it.
+func NewPolicy() (*Policy, error) {
+	embedded, err := embeddedCatalogFS.ReadFile("catalog.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded PVC sizing catalog: %+v", err)
+	}
+
+	var catalog catalogFile
+	if err := yaml.Unmarshal(embedded, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse embedded PVC sizing catalog: %+v", err)
+	}
+
+	if len(OverrideFile) > 0 {
+		overrideBytes, err := ioutil.ReadFile(OverrideFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --pvc-sizing-file '%s': %+v", OverrideFile, err)
+		}
+		var override catalogFile
+		if err := yaml.Unmarshal(overrideBytes, &override); err != nil {
+			return nil, fmt.Errorf("failed to parse --pvc-sizing-file '%s': %+v", OverrideFile, err)
+		}
+		catalog = mergeCatalogs(catalog, override)
+	}
+
+	return &Policy{catalog: catalog}, nil
+}
+
+// mergeCatalogs layers override on top of base, entry by entry, so an
+// override file only needs to list the sizes it wants to change.
+func mergeCatalogs(base, override catalogFile) catalogFile {
+	if base.Products == nil {
+		base.Products = map[string]productCatalog{}
+	}
+	for product, overrideProduct := range override.Products {
+		baseProduct := base.Products[product]
+		if baseProduct.Default == nil {
+			baseProduct.Default = map[string]string{}
+		}
+		for claim, size := range overrideProduct.Default {
+			baseProduct.Default[claim] = size
+		}
+		if len(overrideProduct.Versions) > 0 {
+			if baseProduct.Versions == nil {
+				baseProduct.Versions = map[string]map[string]string{}
+			}
+			for version, sizes := range overrideProduct.Versions {
+				merged := map[string]string{}
+				for claim, size := range baseProduct.Versions[version] {
+					merged[claim] = size
+				}
+				for claim, size := range sizes {
+					merged[claim] = size
+				}
+				baseProduct.Versions[version] = merged
+			}
+		}
+		base.Products[product] = baseProduct
+	}
+	return base
+}
+
+// Default returns the default size for claimName under product/version,
+// preferring a version-specific override over the product's general default.
+func (p *Policy) Default(claimName, product, version string) (resource.Quantity, error) {
+	productCatalog, ok := p.catalog.Products[product]
+	if !ok {
+		return resource.Quantity{}, fmt.Errorf("%w: no PVC sizing catalog entry for product '%s'", ErrNoDefaultSize, product)
+	}
+
+	size, ok := productCatalog.Versions[version][claimName]
+	if !ok {
+		size, ok = productCatalog.Default[claimName]
+	}
+	if !ok {
+		return resource.Quantity{}, fmt.Errorf("%w: no default PVC size for claim '%s' of product '%s'", ErrNoDefaultSize, claimName, product)
+	}
+
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("catalog size '%s' for claim '%s' of product '%s' is not a valid quantity: %+v", size, claimName, product, err)
+	}
+	return quantity, nil
+}
+
+// Snapshot returns the effective size that Default would pick for each of
+// claimNames under product/version, so users can see exactly what will be
+// provisioned before running apply.
+func (p *Policy) Snapshot(product, version string, claimNames []string) (map[string]string, error) {
+	snapshot := make(map[string]string, len(claimNames))
+	for _, claimName := range claimNames {
+		quantity, err := p.Default(claimName, product, version)
+		if err != nil {
+			return nil, err
+		}
+		snapshot[claimName] = quantity.String()
+	}
+	return snapshot, nil
+}
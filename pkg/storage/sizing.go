@@ -0,0 +1,36 @@
+/*
+Copyright (C) 2019 Synopsys, Inc.
+
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements. See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership. The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License. You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied. See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+// Package storage provides a pluggable policy for the default sizes
+// synopsysctl requests for the PVCs it creates (blackduck-postgres,
+// alert-postgres, etc.), replacing a hard-coded per-claim-name switch that
+// could only be changed by recompiling.
+package storage
+
+import "k8s.io/apimachinery/pkg/api/resource"
+
+// SizingPolicy decides the default size for a PVC when the user didn't
+// specify one explicitly. product is the lowercase product name ("blackduck",
+// "alert", "polaris"); version is the product version being deployed, so a
+// policy can vary sizes across versions if it needs to.
+type SizingPolicy interface {
+	Default(claimName, product, version string) (resource.Quantity, error)
+}
@@ -0,0 +1,300 @@
+/*
+Copyright (C) 2019 Synopsys, Inc.
+
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements. See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership. The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License. You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied. See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package synopsysctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// DryRunMode controls how far KubectlApplyRuntimeObjects/KubectlDeleteRuntimeObjects
+// go before reporting their ApplyPlan.
+type DryRunMode string
+
+const (
+	// DryRunOff applies/deletes for real, in addition to building the plan.
+	DryRunOff DryRunMode = "off"
+	// DryRunClient never contacts the API server to mutate anything; the plan
+	// is computed entirely from a Get plus a local patch computation.
+	DryRunClient DryRunMode = "client"
+	// DryRunServer sends the request with the Kubernetes `dryRun=All` option,
+	// so admission/defaulting/validation run server-side but nothing persists.
+	DryRunServer DryRunMode = "server"
+)
+
+// PlanAction is the effect an ApplyPlanEntry will have (or had) on a single object.
+type PlanAction string
+
+const (
+	PlanActionCreate   PlanAction = "Create"
+	PlanActionUpdate   PlanAction = "Update"
+	PlanActionNoChange PlanAction = "NoChange"
+	PlanActionDelete   PlanAction = "Delete"
+)
+
+// ApplyPlanEntry describes what will happen (or happened) to a single object.
+type ApplyPlanEntry struct {
+	GVK       schema.GroupVersionKind
+	Namespace string
+	Name      string
+	Action    PlanAction
+	// Diff is a JSON merge/strategic-merge patch from the live object to the
+	// desired one. Empty for Create (nothing to diff against) and Delete.
+	Diff string
+}
+
+// ApplyPlan is the structured, auditable result of an apply or delete run.
+type ApplyPlan struct {
+	Entries []ApplyPlanEntry
+}
+
+// RenderTable renders the plan as a human-readable table.
+func (p *ApplyPlan) RenderTable() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tKIND\tNAME\tACTION")
+	for _, e := range p.Entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Namespace, e.GVK.Kind, e.Name, e.Action)
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// RenderJSON renders the plan as indented JSON.
+func (p *ApplyPlan) RenderJSON() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// WritePatchFiles writes one <kind>_<namespace>_<name>.patch file per entry
+// that has a non-empty Diff into dir, creating it if necessary.
+func (p *ApplyPlan) WritePatchFiles(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create dry-run output dir '%s': %+v", dir, err)
+	}
+	for _, e := range p.Entries {
+		if len(e.Diff) == 0 {
+			continue
+		}
+		fileName := fmt.Sprintf("%s_%s_%s.patch", e.GVK.Kind, e.Namespace, e.Name)
+		path := filepath.Join(dir, fileName)
+		if err := os.WriteFile(path, []byte(e.Diff), 0644); err != nil {
+			return fmt.Errorf("failed to write patch file '%s': %+v", path, err)
+		}
+	}
+	return nil
+}
+
+// planAndApply builds an ApplyPlan for objects and, when mode is DryRunOff,
+// also performs the real server-side apply. KubectlApplyRuntimeObjects is a
+// thin wrapper around this that discards the plan for callers that only care
+// about the error.
+func planAndApply(objects map[string]runtime.Object, mode DryRunMode) (*ApplyPlan, error) {
+	force := true
+	plan := &ApplyPlan{}
+	var applyErrors []string
+
+	for name, obj := range objects {
+		resource, u, err := dynamicResourceFor(obj)
+		if err != nil {
+			applyErrors = append(applyErrors, fmt.Sprintf("%s: %+v", name, err))
+			continue
+		}
+
+		existing, getErr := resource.Get(context.TODO(), u.GetName(), metav1.GetOptions{})
+		entry := ApplyPlanEntry{
+			GVK:       u.GroupVersionKind(),
+			Namespace: u.GetNamespace(),
+			Name:      u.GetName(),
+		}
+
+		desired, err := u.MarshalJSON()
+		if err != nil {
+			applyErrors = append(applyErrors, fmt.Sprintf("%s: failed to marshal object: %+v", name, err))
+			continue
+		}
+
+		if getErr != nil && !apierrors.IsNotFound(getErr) {
+			applyErrors = append(applyErrors, fmt.Sprintf("%s (%s): %+v", name, entry.GVK.String(), getErr))
+			continue
+		}
+
+		// serverApplied is the object the API server actually produced for a
+		// DryRunServer/DryRunOff patch - including anything admission/defaulting
+		// mutated - so the diff below reflects what the server evaluated, not
+		// just what we asked for.
+		var serverApplied *unstructured.Unstructured
+		if mode != DryRunClient {
+			patchOpts := metav1.PatchOptions{FieldManager: synopsysctlFieldManager, Force: &force}
+			if mode == DryRunServer {
+				patchOpts.DryRun = []string{metav1.DryRunAll}
+			}
+			if mode == DryRunServer || mode == DryRunOff {
+				serverApplied, err = resource.Patch(context.TODO(), u.GetName(), types.ApplyPatchType, desired, patchOpts)
+				if err != nil {
+					applyErrors = append(applyErrors, fmt.Sprintf("%s (%s): %+v", name, entry.GVK.String(), err))
+					continue
+				}
+			}
+		}
+
+		if getErr != nil {
+			entry.Action = PlanActionCreate
+		} else {
+			diffDesiredObj := u
+			if serverApplied != nil {
+				diffDesiredObj = serverApplied
+			}
+			diffDesired, err := diffDesiredObj.MarshalJSON()
+			if err != nil {
+				applyErrors = append(applyErrors, fmt.Sprintf("%s: failed to marshal desired object: %+v", name, err))
+				continue
+			}
+			live, err := projectLiveOntoDesiredShape(existing, diffDesiredObj).MarshalJSON()
+			if err != nil {
+				applyErrors = append(applyErrors, fmt.Sprintf("%s: failed to marshal live object: %+v", name, err))
+				continue
+			}
+			patch, err := strategicOrMergePatch(live, diffDesired, obj)
+			if err != nil {
+				applyErrors = append(applyErrors, fmt.Sprintf("%s: failed to diff live/desired object: %+v", name, err))
+				continue
+			}
+			entry.Diff = string(patch)
+			if len(entry.Diff) <= 2 { // "{}" - no-op patch
+				entry.Action = PlanActionNoChange
+			} else {
+				entry.Action = PlanActionUpdate
+			}
+		}
+
+		plan.Entries = append(plan.Entries, entry)
+	}
+
+	if len(applyErrors) > 0 {
+		return plan, fmt.Errorf("failed to apply %d runtime object(s):\n%s", len(applyErrors), strings.Join(applyErrors, "\n"))
+	}
+	return plan, nil
+}
+
+// planAndDelete builds an ApplyPlan for a delete of objects and, when mode is
+// DryRunOff, also performs the real delete.
+func planAndDelete(objects map[string]runtime.Object, mode DryRunMode) (*ApplyPlan, error) {
+	plan := &ApplyPlan{}
+	var deleteErrors []string
+
+	for name, obj := range objects {
+		resource, u, err := dynamicResourceFor(obj)
+		if err != nil {
+			deleteErrors = append(deleteErrors, fmt.Sprintf("%s: %+v", name, err))
+			continue
+		}
+
+		entry := ApplyPlanEntry{
+			GVK:       u.GroupVersionKind(),
+			Namespace: u.GetNamespace(),
+			Name:      u.GetName(),
+			Action:    PlanActionDelete,
+		}
+
+		if mode != DryRunClient {
+			deleteOpts := metav1.DeleteOptions{}
+			if mode == DryRunServer {
+				deleteOpts.DryRun = []string{metav1.DryRunAll}
+			}
+			if mode == DryRunServer || mode == DryRunOff {
+				err = resource.Delete(context.TODO(), u.GetName(), deleteOpts)
+				if err != nil && !apierrors.IsNotFound(err) {
+					deleteErrors = append(deleteErrors, fmt.Sprintf("%s (%s): %+v", name, entry.GVK.String(), err))
+					continue
+				}
+			}
+		}
+
+		plan.Entries = append(plan.Entries, entry)
+	}
+
+	if len(deleteErrors) > 0 {
+		return plan, fmt.Errorf("failed to delete %d runtime object(s):\n%s", len(deleteErrors), strings.Join(deleteErrors, "\n"))
+	}
+	return plan, nil
+}
+
+// projectLiveOntoDesiredShape returns a copy of live containing only the
+// fields present in desired, recursing into nested objects. Diffing the raw
+// live object against desired would always show a "change" - status,
+// metadata.resourceVersion/uid/managedFields, and server-defaulted spec
+// fields (e.g. a Service's clusterIP) are all fields synopsysctl doesn't
+// set and desired never mentions, so a fresh Get would never round-trip to
+// an empty patch. Restricting live to desired's shape keeps the diff scoped
+// to fields synopsysctl actually manages, so an unmodified object correctly
+// produces a "{}" patch and resolves to PlanActionNoChange.
+func projectLiveOntoDesiredShape(live, desired *unstructured.Unstructured) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: projectMapOntoShape(live.Object, desired.Object)}
+}
+
+func projectMapOntoShape(live, desired map[string]interface{}) map[string]interface{} {
+	projected := map[string]interface{}{}
+	for key, desiredVal := range desired {
+		liveVal, ok := live[key]
+		if !ok {
+			continue
+		}
+		if desiredMap, ok := desiredVal.(map[string]interface{}); ok {
+			if liveMap, ok := liveVal.(map[string]interface{}); ok {
+				projected[key] = projectMapOntoShape(liveMap, desiredMap)
+				continue
+			}
+		}
+		projected[key] = liveVal
+	}
+	return projected
+}
+
+// strategicOrMergePatch diffs live against desired using a strategic merge
+// patch when obj is a typed built-in (so field-merge semantics like
+// add-don't-replace on lists are respected), falling back to a generic JSON
+// merge patch for CRDs/unstructured objects where no Go type is known.
+func strategicOrMergePatch(live, desired []byte, obj runtime.Object) ([]byte, error) {
+	if _, isUnstructured := obj.(*unstructured.Unstructured); !isUnstructured && obj != nil {
+		dataStruct := reflect.New(reflect.TypeOf(obj).Elem()).Interface()
+		if patch, err := strategicpatch.CreateTwoWayMergePatch(live, desired, dataStruct); err == nil {
+			return patch, nil
+		}
+	}
+	return jsonpatch.CreateMergePatch(live, desired)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright (C) 2019 Synopsys, Inc.
+
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements. See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership. The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License. You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied. See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package synopsysctl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+)
+
+// HelmRenderer loads and renders a Helm chart in-process, replacing the old
+// pattern of swapping a resource URL and letting downstream code fetch it as
+// a tarball itself - UpdateHelmChartLocation now loads through
+// HelmRenderer.LoadChart once the cluster rest.Config is available. It is
+// designed to be shared by the blackduck, alert, and polaris commands as
+// they pick up Render/InstallOrUpgrade too - only the chart name, version,
+// and curated default values differ between them.
+type HelmRenderer struct {
+	actionConfig *action.Configuration
+	settings     *cli.EnvSettings
+}
+
+// NewHelmRenderer builds a HelmRenderer bound to the given namespace, reusing
+// synopsysctl's existing rest.Config rather than letting Helm re-read a
+// kubeconfig from disk.
+func NewHelmRenderer(namespace string) (*HelmRenderer, error) {
+	settings := cli.New()
+	settings.SetNamespace(namespace)
+
+	configFlags := genericclioptions.NewConfigFlags(false)
+	configFlags.WrapConfigFn = func(*rest.Config) *rest.Config { return restconfig }
+
+	actionConfig := new(action.Configuration)
+	if err := actionConfig.Init(configFlags, namespace, "secret", log.Debugf); err != nil {
+		return nil, fmt.Errorf("failed to initialize helm action config for namespace '%s': %+v", namespace, err)
+	}
+
+	return &HelmRenderer{actionConfig: actionConfig, settings: settings}, nil
+}
+
+// LoadChart loads a chart from a local path, an OCI registry reference
+// (oci://...), or a classic HTTP chart repo URL/reference (repo/chart).
+func (r *HelmRenderer) LoadChart(chartLocation, chartVersion string) (*chart.Chart, error) {
+	client := action.NewInstall(r.actionConfig)
+	client.ChartPathOptions.Version = chartVersion
+
+	chartPath, err := client.ChartPathOptions.LocateChart(chartLocation, r.settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate chart '%s': %+v", chartLocation, err)
+	}
+
+	loadedChart, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart '%s': %+v", chartPath, err)
+	}
+	return loadedChart, nil
+}
+
+// MergeValues layers user-supplied --set/--set-file/--values overrides on top
+// of the module's curated defaults for a product, giving the overrides
+// precedence.
+func (r *HelmRenderer) MergeValues(defaults map[string]interface{}, opts *values.Options) (map[string]interface{}, error) {
+	userValues, err := opts.MergeValues(getter.All(r.settings))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge --set/--set-file/--values overrides: %+v", err)
+	}
+	return chartutil.CoalesceTables(userValues, defaults), nil
+}
+
+// Render renders chartLocation with mergedValues into a map of fully
+// rendered runtime.Objects keyed by "<kind>/<name>", the same shape
+// KubectlApplyRuntimeObjects expects. The map does not keep Helm's kind
+// order (see manifestToRuntimeObjects). It performs a client-only dry-run
+// install so templating/hook-ordering matches what `helm install` would
+// produce without actually touching the cluster.
+func (r *HelmRenderer) Render(loadedChart *chart.Chart, releaseName, namespace string, mergedValues map[string]interface{}) (map[string]runtime.Object, error) {
+	client := action.NewInstall(r.actionConfig)
+	client.ReleaseName = releaseName
+	client.Namespace = namespace
+	client.DryRun = true
+	client.ClientOnly = true
+	client.Replace = true
+	client.IncludeCRDs = true
+
+	rel, err := client.Run(loadedChart, mergedValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart '%s': %+v", loadedChart.Name(), err)
+	}
+
+	return manifestToRuntimeObjects(rel)
+}
+
+// InstallOrUpgrade renders and applies loadedChart against the cluster,
+// running a Helm install if releaseName has no prior release history, or an
+// upgrade otherwise. It returns the same rendered object map as Render so
+// callers can still apply/verify through KubectlApplyRuntimeObjects.
+func (r *HelmRenderer) InstallOrUpgrade(loadedChart *chart.Chart, releaseName, namespace string, mergedValues map[string]interface{}) (map[string]runtime.Object, error) {
+	histClient := action.NewHistory(r.actionConfig)
+	histClient.Max = 1
+	_, histErr := histClient.Run(releaseName)
+
+	var rel *release.Release
+	var err error
+	switch {
+	case errors.Is(histErr, driver.ErrReleaseNotFound):
+		client := action.NewInstall(r.actionConfig)
+		client.ReleaseName = releaseName
+		client.Namespace = namespace
+		client.IncludeCRDs = true
+		rel, err = client.Run(loadedChart, mergedValues)
+	case histErr != nil:
+		return nil, fmt.Errorf("failed to look up release history for '%s': %+v", releaseName, histErr)
+	default:
+		client := action.NewUpgrade(r.actionConfig)
+		client.Namespace = namespace
+		rel, err = client.Run(releaseName, loadedChart, mergedValues)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to install/upgrade release '%s': %+v", releaseName, err)
+	}
+
+	return manifestToRuntimeObjects(rel)
+}
+
+// manifestToRuntimeObjects splits a rendered release's combined manifest into
+// individual YAML documents, sorts them using Helm's own kind-ordering and
+// decodes each into an unstructured.Unstructured. The objects are returned in
+// a map, so that order is not preserved for the caller.
+func manifestToRuntimeObjects(rel *release.Release) (map[string]runtime.Object, error) {
+	manifest := rel.Manifest
+	for _, hook := range rel.Hooks {
+		manifest += "\n---\n" + hook.Manifest
+	}
+
+	sortedHooks, sortedManifests, err := releaseutil.SortManifests(map[string]string{rel.Name: manifest}, nil, releaseutil.InstallOrder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sort rendered manifests for release '%s': %+v", rel.Name, err)
+	}
+	// SortManifests returns hook documents separately; decode them as well.
+	for _, hook := range sortedHooks {
+		sortedManifests = append(sortedManifests, releaseutil.Manifest{Name: hook.Path, Content: hook.Manifest})
+	}
+
+	objects := map[string]runtime.Object{}
+	for _, m := range sortedManifests {
+		content := strings.TrimSpace(m.Content)
+		if len(content) == 0 {
+			continue
+		}
+
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(content), &u.Object); err != nil {
+			return nil, fmt.Errorf("failed to decode rendered manifest '%s': %+v", m.Name, err)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+		objects[fmt.Sprintf("%s/%s", u.GetKind(), u.GetName())] = u
+	}
+	return objects, nil
+}
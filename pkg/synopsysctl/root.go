@@ -0,0 +1,67 @@
+/*
+Copyright (C) 2019 Synopsys, Inc.
+
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements. See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership. The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License. You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied. See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package synopsysctl
+
+import (
+	"github.com/blackducksoftware/synopsysctl/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+// kubeConfigPath, insecureSkipTLSVerify, and logLevelCtl back the global
+// --kubeconfig, --insecure-skip-tls-verify, and --ctl-log-level flags that
+// the rest of this package (setGlobalKubeConfigPath, setGlobalRestConfig,
+// setSynopsysctlLogLevel) already reads.
+var kubeConfigPath string
+var insecureSkipTLSVerify bool
+var logLevelCtl string
+
+// NewRootCmd builds the root `synopsysctl` command and registers the global
+// cluster-access flags every subcommand depends on, then bootstraps the
+// kube client state (rest config, kubeClient, dynamicClient/restMapper)
+// before any subcommand runs.
+func NewRootCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "synopsysctl",
+		Short: "Manage Synopsys products in a cluster",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := setSynopsysctlLogLevel(); err != nil {
+				return err
+			}
+			if err := setGlobalKubeConfigPath(cmd); err != nil {
+				return err
+			}
+			if err := setGlobalRestConfig(); err != nil {
+				return err
+			}
+			return setGlobalKubeClient()
+		},
+	}
+
+	rootCmd.PersistentFlags().StringVar(&kubeConfigPath, "kubeconfig", "", "path to the kubeconfig file to use (defaults to $KUBECONFIG, then the in-cluster config, then $HOME/.kube/config)")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "name of the kubeconfig context to use (defaults to the kubeconfig's current-context)")
+	rootCmd.PersistentFlags().StringVar(&clusterName, "cluster", "", "name of the kubeconfig cluster to use (defaults to the chosen context's cluster)")
+	rootCmd.PersistentFlags().BoolVar(&insecureSkipTLSVerify, "insecure-skip-tls-verify", false, "skip TLS verification for the cluster connection")
+	rootCmd.PersistentFlags().StringVar(&logLevelCtl, "ctl-log-level", "info", "log level for synopsysctl itself (trace, debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&storage.OverrideFile, "pvc-sizing-file", "", "path to a YAML/JSON catalog of PVC default sizes that overrides the built-in catalog")
+
+	return rootCmd
+}
@@ -22,9 +22,7 @@ under the License.
 package synopsysctl
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -33,19 +31,53 @@ import (
 	"github.com/blackducksoftware/synopsysctl/pkg/api"
 	"github.com/blackducksoftware/synopsysctl/pkg/globals"
 	"github.com/blackducksoftware/synopsysctl/pkg/util"
+	routev1 "github.com/openshift/api/route/v1"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"helm.sh/helm/v3/pkg/chart"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	// registers the oidc, gcp, azure, and exec-plugin auth providers so
+	// kubeconfig entries using them (AuthProvider/ExecConfig) work out of
+	// the box instead of failing with "no Auth Provider found".
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
+// synopsysctlFieldManager is the field manager identity used when applying
+// objects via server-side apply. It lets the API server track which fields
+// synopsysctl owns as distinct from fields set by other actors (e.g. HPAs,
+// admission webhooks, or a user editing the object directly).
+const synopsysctlFieldManager = "synopsysctl"
+
 var restconfig *rest.Config
 var kubeClient *kubernetes.Clientset
+var dynamicClient dynamic.Interface
+var restMapper meta.RESTMapper
+
+// contextName and clusterName back the --context and --cluster flags (see
+// root.go), letting users with multi-context kubeconfigs target a specific
+// cluster/context without editing their kubeconfig file.
+var contextName string
+var clusterName string
+
+// restConfigSource records which path produced 'restconfig' - "in-cluster",
+// "file", or "env" - purely for logging/debugging; it has no effect on
+// behavior.
+var restConfigSource string
 
 // setSynopsysctlLogLevel sets the binary's log level to the value stored in logLevelCtl
 func setSynopsysctlLogLevel() error {
@@ -76,26 +108,65 @@ func setGlobalKubeConfigPath(cmd *cobra.Command) error {
 	return nil
 }
 
-// GetKubeClientFromOutsideCluster returns the rest config of outside cluster
-func GetKubeClientFromOutsideCluster(kubeconfigpath string, insecureSkipTLSVerify bool) (*rest.Config, error) {
+// GetKubeClientFromOutsideCluster returns the rest config for accessing a
+// cluster. When kubeconfigpath is empty and KUBECONFIG isn't set, it first
+// tries the in-cluster config (serviceaccount token + CA mounted into a Pod)
+// so synopsysctl can run as a Pod-based controller, then falls back to the
+// default kubeconfig file. context/cluster override the current-context and
+// cluster from a kubeconfig with multiple entries; AuthProvider (oidc, gcp,
+// azure) and ExecConfig (exec-plugin) entries in the kubeconfig are honored
+// automatically once the auth plugins are registered (see the blank
+// k8s.io/client-go/plugin/pkg/client/auth import above).
+func GetKubeClientFromOutsideCluster(kubeconfigpath string, insecureSkipTLSVerify bool, context, cluster string) (*rest.Config, error) {
+	if len(kubeconfigpath) == 0 {
+		if _, envSet := os.LookupEnv("KUBECONFIG"); !envSet {
+			if inClusterConfig, err := rest.InClusterConfig(); err == nil {
+				restConfigSource = "in-cluster"
+				if insecureSkipTLSVerify {
+					// client-go rejects a config that sets both Insecure and a
+					// root CA (the mounted serviceaccount CA populates CAFile
+					// here), so clear it before opting into insecure mode.
+					inClusterConfig.Insecure = true
+					inClusterConfig.CAFile = ""
+					inClusterConfig.CAData = nil
+				}
+				return inClusterConfig, nil
+			}
+			log.Debugf("no in-cluster config found, falling back to kubeconfig")
+		}
+	}
+
 	// Determine Config Paths
 	if home := homeDir(); len(kubeconfigpath) == 0 && home != "" {
 		kubeconfigpath = filepath.Join(home, ".kube", "config")
 	}
 
+	overrides := &clientcmd.ConfigOverrides{
+		ClusterInfo: clientcmdapi.Cluster{
+			Server:                "",
+			InsecureSkipTLSVerify: insecureSkipTLSVerify,
+		},
+	}
+	if len(context) > 0 {
+		overrides.CurrentContext = context
+	}
+	if len(cluster) > 0 {
+		overrides.Context.Cluster = cluster
+	}
+
 	kubeConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
 		&clientcmd.ClientConfigLoadingRules{
 			ExplicitPath: kubeconfigpath,
 		},
-		&clientcmd.ConfigOverrides{
-			ClusterInfo: clientcmdapi.Cluster{
-				Server:                "",
-				InsecureSkipTLSVerify: insecureSkipTLSVerify,
-			},
-		}).ClientConfig()
+		overrides).ClientConfig()
 	if err != nil {
 		return nil, err
 	}
+
+	restConfigSource = "file"
+	if envVal, envSet := os.LookupEnv("KUBECONFIG"); envSet && envVal == kubeconfigpath {
+		restConfigSource = "env"
+	}
 	return kubeConfig, nil
 }
 
@@ -110,22 +181,25 @@ func homeDir() string {
 // setGlobalRestConfig sets the global variable 'restconfig' for other commands to use
 func setGlobalRestConfig() error {
 	var err error
-	restconfig, err = GetKubeClientFromOutsideCluster(kubeConfigPath, insecureSkipTLSVerify)
-	log.Debugf("rest config: %+v", restconfig)
+	restconfig, err = GetKubeClientFromOutsideCluster(kubeConfigPath, insecureSkipTLSVerify, contextName, clusterName)
 	if err != nil {
 		return err
 	}
+	log.Debugf("rest config (source: %s): %+v", restConfigSource, restconfig)
 	return nil
 }
 
-// setGlobalKubeClient sets the global variable 'kubeClient' for other commands to use
+// setGlobalKubeClient sets the global variable 'kubeClient' for other commands to use,
+// then sets up the dynamic client + RESTMapper pair (see setGlobalDynamicClient)
+// that KubectlApplyRuntimeObjects/KubectlDeleteRuntimeObjects depend on, so every
+// caller that bootstraps a kubeClient also gets a usable dynamic client.
 func setGlobalKubeClient() error {
 	var err error
 	kubeClient, err = getKubeClient(restconfig)
 	if err != nil {
 		return err
 	}
-	return nil
+	return setGlobalDynamicClient()
 }
 
 // getKubeClient gets the kubernetes client
@@ -137,6 +211,24 @@ func getKubeClient(kubeConfig *rest.Config) (*kubernetes.Clientset, error) {
 	return client, nil
 }
 
+// setGlobalDynamicClient sets the global variables 'dynamicClient' and 'restMapper'
+// used by KubectlApplyRuntimeObjects/KubectlDeleteRuntimeObjects to talk to the
+// API server directly instead of shelling out to kubectl/oc.
+func setGlobalDynamicClient() error {
+	var err error
+	dynamicClient, err = dynamic.NewForConfig(restconfig)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %+v", err)
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restconfig)
+	if err != nil {
+		return fmt.Errorf("failed to create discovery client: %+v", err)
+	}
+	restMapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+	return nil
+}
+
 // DetermineClusterClients returns bool values for which client
 // to use. They will never both be true
 func DetermineClusterClients(restConfig *rest.Config, kubeClient *kubernetes.Clientset) (kube, openshift bool) {
@@ -204,45 +296,6 @@ func getKubeExecCmd(restconfig *rest.Config, kubeClient *kubernetes.Clientset, a
 	}
 }
 
-// RunKubeCmd is a simple wrapper to oc/kubectl exec that captures output.
-// TODO consider replacing w/ go api but not crucial for now.
-func RunKubeCmd(restconfig *rest.Config, kubeClient *kubernetes.Clientset, args ...string) (string, error) {
-	cmd2, err := getKubeExecCmd(restconfig, kubeClient, args...)
-	if err != nil {
-		return "", err
-	}
-
-	stdoutErr, err := cmd2.CombinedOutput()
-	if err != nil {
-		return string(stdoutErr), err
-	}
-	return string(stdoutErr), nil
-}
-
-// RunKubeCmdWithStdin is a simple wrapper to kubectl exec command with standard input
-func RunKubeCmdWithStdin(restconfig *rest.Config, kubeClient *kubernetes.Clientset, stdin string, args ...string) (string, error) {
-	cmd2, err := getKubeExecCmd(restconfig, kubeClient, args...)
-	if err != nil {
-		return "", err
-	}
-
-	stdinPipe, err := cmd2.StdinPipe()
-	if err != nil {
-		return "", err
-	}
-
-	go func() {
-		defer stdinPipe.Close()
-		io.WriteString(stdinPipe, stdin)
-	}()
-
-	stdoutErr, err := cmd2.CombinedOutput()
-	if err != nil {
-		return string(stdoutErr), err
-	}
-	return string(stdoutErr), nil
-}
-
 // RunKubeEditorCmd is a wrapper for oc/kubectl but redirects
 // input/output to the user - ex: let user control text editor
 func RunKubeEditorCmd(restConfig *rest.Config, kubeClient *kubernetes.Clientset, args ...string) error {
@@ -277,58 +330,114 @@ func RunKubeEditorCmd(restConfig *rest.Config, kubeClient *kubernetes.Clientset,
 	return nil
 }
 
-// KubectlApplyRuntimeObjects creates runtime objects by converting them to bytes
-// and passing them through the kubectl command
-func KubectlApplyRuntimeObjects(objects map[string]runtime.Object) error {
-	var content []byte
-	for _, obj := range objects {
-		secretBytes, err := json.Marshal(obj)
-		if err != nil {
-			return err
-		}
-		content = append(content, secretBytes...)
+// dynamicResourceFor returns the namespace-scoped or cluster-scoped dynamic
+// resource interface for obj, resolving its GVK to a GVR through the
+// discovery-backed RESTMapper instead of assuming a REST path.
+func dynamicResourceFor(obj runtime.Object) (dynamic.ResourceInterface, *unstructured.Unstructured, error) {
+	u, err := toUnstructured(obj)
+	if err != nil {
+		return nil, nil, err
 	}
-	out, err := RunKubeCmdWithStdin(restconfig, kubeClient, string(content), "apply", "--validate=false", "-f", "-")
+
+	gvk := u.GroupVersionKind()
+	mapping, err := restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
 	if err != nil {
-		return fmt.Errorf("failed to deploy Runtime Object: %+v : %+v", out, err)
+		return nil, nil, fmt.Errorf("no REST mapping for %s: %+v", gvk.String(), err)
 	}
-	return nil
-}
 
-// KubectlDeleteRuntimeObjects deletes runtime objects by converting them to bytes
-// and passing them through the kubectl command
-func KubectlDeleteRuntimeObjects(objects map[string]runtime.Object) error {
-	var content []byte
-	for _, obj := range objects {
-		secretBytes, err := json.Marshal(obj)
-		if err != nil {
-			return err
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ns := u.GetNamespace()
+		if len(ns) == 0 {
+			ns = metav1.NamespaceDefault
 		}
-		content = append(content, secretBytes...)
+		return dynamicClient.Resource(mapping.Resource).Namespace(ns), u, nil
 	}
-	out, err := RunKubeCmdWithStdin(restconfig, kubeClient, string(content), "delete", "-f", "-")
+	return dynamicClient.Resource(mapping.Resource), u, nil
+}
+
+// toUnstructured converts a typed runtime.Object into an *unstructured.Unstructured
+// so it can be sent through the dynamic client, which is GVK/GVR agnostic.
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u, nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
 	if err != nil {
-		return fmt.Errorf("failed to delete Runtime Object: %+v : %+v", out, err)
+		return nil, fmt.Errorf("failed to convert object to unstructured: %+v", err)
 	}
-	return nil
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// KubectlApplyRuntimeObjects server-side applies a set of runtime objects
+// directly against the API server through the dynamic client + RESTMapper,
+// rather than shelling out to kubectl/oc. Each object is applied independently
+// under the "synopsysctl" field manager so a failure on one object doesn't
+// block or get masked by the others; all per-object errors are collected and
+// returned together.
+//
+// For the structured, auditable plan of what will change (used by
+// `--dry-run=client|server`), see KubectlApplyRuntimeObjectsWithPlan.
+func KubectlApplyRuntimeObjects(objects map[string]runtime.Object) error {
+	_, err := KubectlApplyRuntimeObjectsWithPlan(objects, DryRunOff)
+	return err
+}
+
+// KubectlApplyRuntimeObjectsWithPlan is KubectlApplyRuntimeObjects plus a
+// DryRunMode: DryRunOff applies for real (as before), DryRunClient only diffs
+// locally against the live objects, and DryRunServer sends the apply with the
+// API server's dryRun=All option so the server-evaluated result can be
+// diffed without persisting anything. In every mode it returns the resulting
+// ApplyPlan so callers (e.g. `--dry-run=server`) can render it as a table,
+// JSON, or a patch file per object.
+func KubectlApplyRuntimeObjectsWithPlan(objects map[string]runtime.Object, mode DryRunMode) (*ApplyPlan, error) {
+	return planAndApply(objects, mode)
 }
 
-// UpdateHelmChartLocation uses --app-resources-path and chartVersion to update the value at *chartVariable. This value is originally set
-// when synopsysctl starts (see file pkg/globals/helmglobalvalues.go)
-func UpdateHelmChartLocation(flags *pflag.FlagSet, chartName, appVersion string, chartVariable *string) error {
+// KubectlDeleteRuntimeObjects deletes a set of runtime objects directly
+// against the API server through the dynamic client + RESTMapper. A missing
+// object (already deleted) is not treated as an error so delete remains
+// idempotent, matching the old `kubectl delete -f -` behavior.
+func KubectlDeleteRuntimeObjects(objects map[string]runtime.Object) error {
+	_, err := KubectlDeleteRuntimeObjectsWithPlan(objects, DryRunOff)
+	return err
+}
+
+// KubectlDeleteRuntimeObjectsWithPlan is KubectlDeleteRuntimeObjects plus a
+// DryRunMode, mirroring KubectlApplyRuntimeObjectsWithPlan.
+func KubectlDeleteRuntimeObjectsWithPlan(objects map[string]runtime.Object, mode DryRunMode) (*ApplyPlan, error) {
+	return planAndDelete(objects, mode)
+}
+
+// UpdateHelmChartLocation uses --app-resources-path and appVersion to
+// resolve the chart location for chartName into *chartVariable - from
+// --app-resources-path if it was passed, otherwise from the chart index for
+// appVersion (see file pkg/globals/helmglobalvalues.go) - then loads that
+// chart in-process via HelmRenderer and returns it, so the caller gets a
+// validated *chart.Chart instead of having to fetch and untar
+// *chartVariable itself. Returns a nil chart (with no error) if called
+// before the cluster rest.Config is set up, since *chartVariable is all a
+// caller running that early can use anyway.
+func UpdateHelmChartLocation(flags *pflag.FlagSet, chartName, appVersion string, chartVariable *string) (*chart.Chart, error) {
 	chartLocationFlag := flags.Lookup("app-resources-path")
 	if chartLocationFlag.Changed {
 		*chartVariable = chartLocationFlag.Value.String()
-	} else {
-		if len(appVersion) > 0 {
-			chartURL, err := util.GetLatestChartURLForAppVersion(globals.IndexChartURLs, chartName, appVersion)
-			if err != nil {
-				return fmt.Errorf("failed to get resources version for '%s': %+v", chartName, err)
-			}
-			*chartVariable = chartURL
+	} else if len(appVersion) > 0 {
+		chartURL, err := util.GetLatestChartURLForAppVersion(globals.IndexChartURLs, chartName, appVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get resources version for '%s': %+v", chartName, err)
 		}
+		*chartVariable = chartURL
 	}
-	return nil
+
+	if len(*chartVariable) == 0 || restconfig == nil {
+		return nil, nil
+	}
+
+	renderer, err := NewHelmRenderer("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize helm renderer for '%s': %+v", chartName, err)
+	}
+	return renderer.LoadChart(*chartVariable, appVersion)
 }
 
 func cleanAlertHelmError(errString, releaseName, alertName string) string {
@@ -352,3 +461,148 @@ func NewPVCVolume(config api.PVCVolumeConfig) *api.Volume {
 
 	return &api.Volume{&v}
 }
+
+// NewExposedEndpoint creates the runtime.Objects needed to expose a service
+// outside the cluster, choosing an OpenShift Route or a Kubernetes Ingress
+// based on DetermineClusterClients' openshift/kube result. Callers (e.g.
+// blackduck.init) no longer need to hand-roll a LoadBalancer Service per
+// platform - they declare the exposure they want and get back whichever
+// object(s) the target platform understands. On Kubernetes, if TLS material
+// is supplied, the backing "kubernetes.io/tls" Secret the Ingress references
+// is included too - OpenShift Routes don't need this since they carry the
+// PEM material inline.
+func NewExposedEndpoint(config api.ExposeConfig) ([]runtime.Object, error) {
+	kube, openshift := DetermineClusterClients(restconfig, kubeClient)
+
+	if openshift {
+		return []runtime.Object{newRoute(config)}, nil
+	}
+	if kube {
+		ingress, tlsSecret := newIngress(config)
+		objects := []runtime.Object{ingress}
+		if tlsSecret != nil {
+			objects = append(objects, tlsSecret)
+		}
+		return objects, nil
+	}
+	return nil, fmt.Errorf("couldn't determine if running in Openshift or Kubernetes")
+}
+
+// newRoute builds an OpenShift route.openshift.io/v1 Route for config.
+func newRoute(config api.ExposeConfig) *routev1.Route {
+	route := &routev1.Route{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Route",
+			APIVersion: "route.openshift.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        config.Name,
+			Namespace:   config.Namespace,
+			Annotations: config.Annotations,
+		},
+		Spec: routev1.RouteSpec{
+			Host: config.Hostname,
+			To: routev1.RouteTargetReference{
+				Kind:   "Service",
+				Name:   config.ServiceName,
+				Weight: func(w int32) *int32 { return &w }(100),
+			},
+			Port: &routev1.RoutePort{
+				TargetPort: intstr.FromInt(int(config.ServicePort)),
+			},
+			WildcardPolicy: routev1.WildcardPolicyType(config.WildcardPolicy),
+		},
+	}
+
+	if len(config.TLSTermination) > 0 {
+		route.Spec.TLS = &routev1.TLSConfig{
+			Termination:                   routev1.TLSTerminationType(config.TLSTermination),
+			Certificate:                   config.TLSCert,
+			Key:                           config.TLSKey,
+			CACertificate:                 config.TLSCABundle,
+			InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect,
+		}
+	}
+	return route
+}
+
+// newIngress builds a Kubernetes networking.k8s.io/v1 Ingress for config. When
+// config carries TLS material, it also builds the "kubernetes.io/tls" Secret
+// the Ingress's TLS entry references - the caller is responsible for
+// including that Secret alongside the Ingress (see NewExposedEndpoint).
+func newIngress(config api.ExposeConfig) (*networkingv1.Ingress, *corev1.Secret) {
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Ingress",
+			APIVersion: "networking.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        config.Name,
+			Namespace:   config.Namespace,
+			Annotations: config.Annotations,
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: config.Hostname,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     "/",
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: config.ServiceName,
+											Port: networkingv1.ServiceBackendPort{
+												Number: config.ServicePort,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if len(config.IngressClassName) > 0 {
+		ingress.Spec.IngressClassName = &config.IngressClassName
+	}
+
+	var tlsSecret *corev1.Secret
+	if len(config.TLSCert) > 0 || len(config.TLSKey) > 0 {
+		secretName := config.Name + "-tls"
+		ingress.Spec.TLS = []networkingv1.IngressTLS{
+			{
+				Hosts:      []string{config.Hostname},
+				SecretName: secretName,
+			},
+		}
+
+		secretData := map[string][]byte{
+			corev1.TLSCertKey:       []byte(config.TLSCert),
+			corev1.TLSPrivateKeyKey: []byte(config.TLSKey),
+		}
+		if len(config.TLSCABundle) > 0 {
+			secretData["ca.crt"] = []byte(config.TLSCABundle)
+		}
+
+		tlsSecret = &corev1.Secret{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Secret",
+				APIVersion: "v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: config.Namespace,
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: secretData,
+		}
+	}
+	return ingress, tlsSecret
+}